@@ -0,0 +1,65 @@
+// See LICENSE file for copyright and license details.
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/alecthomas/chroma"
+	chromahtml "github.com/alecthomas/chroma/formatters/html"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/alecthomas/chroma/styles"
+	"github.com/gomarkdown/markdown/ast"
+	mdhtml "github.com/gomarkdown/markdown/html"
+)
+
+// chromaRenderHook returns a RenderNodeHook that intercepts fenced code
+// blocks and runs them through Chroma instead of letting gomarkdown emit
+// a plain <pre><code>.
+func chromaRenderHook(style string, classes bool) mdhtml.RenderNodeFunc {
+	return func(w io.Writer, node ast.Node, entering bool) (ast.WalkStatus, bool) {
+		cb, ok := node.(*ast.CodeBlock)
+		if !ok || !entering {
+			return ast.GoToNext, false
+		}
+		highlightCodeBlock(w, cb, style, classes)
+		return ast.GoToNext, true
+	}
+}
+
+func highlightCodeBlock(w io.Writer, cb *ast.CodeBlock, style string, classes bool) {
+	lexer := chromaLexerFor(cb.Info)
+	it, err := lexer.Tokenise(nil, string(cb.Literal))
+	dieOnError(err)
+	formatter := chromahtml.New(chromaFormatterOpts(classes)...)
+	dieOnError(formatter.Format(w, styles.Get(style), it))
+}
+
+func chromaLexerFor(info []byte) chroma.Lexer {
+	var lexer chroma.Lexer
+	if fields := strings.Fields(string(info)); len(fields) > 0 {
+		lexer = lexers.Get(fields[0])
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	return chroma.Coalesce(lexer)
+}
+
+func chromaFormatterOpts(classes bool) []chromahtml.Option {
+	if classes {
+		return []chromahtml.Option{chromahtml.WithClasses(true)}
+	}
+	return nil
+}
+
+// writeChromaCSS writes the stylesheet for style to path, for use
+// alongside -chroma-classes.
+func writeChromaCSS(path, style string) {
+	out, err := os.Create(path)
+	dieOnError(err)
+	defer out.Close()
+	formatter := chromahtml.New(chromahtml.WithClasses(true))
+	dieOnError(formatter.WriteCSS(out, styles.Get(style)))
+}
@@ -0,0 +1,121 @@
+// See LICENSE file for copyright and license details.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// FrontMatter holds the metadata block at the top of a markdown file, as
+// popularized by Hugo/Jekyll. It may be delimited by --- (YAML), +++
+// (TOML), or a bare {...} object (JSON). Anything besides the known
+// fields below ends up in Params.
+type FrontMatter struct {
+	Title   string                 `yaml:"title" toml:"title" json:"title"`
+	Date    time.Time              `yaml:"date" toml:"date" json:"date"`
+	Draft   bool                   `yaml:"draft" toml:"draft" json:"draft"`
+	Weight  int                    `yaml:"weight" toml:"weight" json:"weight"`
+	Menu    string                 `yaml:"menu" toml:"menu" json:"menu"`
+	Aliases []string               `yaml:"aliases" toml:"aliases" json:"aliases"`
+	Params  map[string]interface{} `yaml:"-" toml:"-" json:"-"`
+}
+
+var knownFrontMatterKeys = map[string]bool{
+	"title": true, "date": true, "draft": true,
+	"weight": true, "menu": true, "aliases": true,
+}
+
+// parseFrontMatter splits dat into a FrontMatter and the remaining body
+// that should be handed to the markdown renderer. If dat does not start
+// with a recognised delimiter, it returns a zero FrontMatter and dat
+// unchanged.
+func parseFrontMatter(dat []byte) (FrontMatter, []byte) {
+	switch {
+	case bytes.HasPrefix(dat, []byte("---")):
+		if raw, body, ok := splitDelimited(dat, "---"); ok {
+			return decodeFrontMatter(raw, yaml.Unmarshal), body
+		}
+	case bytes.HasPrefix(dat, []byte("+++")):
+		if raw, body, ok := splitDelimited(dat, "+++"); ok {
+			return decodeFrontMatter(raw, tomlUnmarshal), body
+		}
+	case bytes.HasPrefix(dat, []byte("{")):
+		if fm, body, ok := splitJSONFrontMatter(dat); ok {
+			return fm, body
+		}
+	}
+	return FrontMatter{}, dat
+}
+
+// splitDelimited extracts the block between a pair of lines that
+// consist solely of delim (e.g. "---" or "+++") and returns it along
+// with everything after the closing delimiter line.
+func splitDelimited(dat []byte, delim string) (raw, body []byte, ok bool) {
+	s := string(dat)
+	nl := strings.IndexByte(s, '\n')
+	if nl < 0 || strings.TrimRight(s[:nl], "\r") != delim {
+		return nil, nil, false
+	}
+	rest := s[nl+1:]
+	end := strings.Index(rest, "\n"+delim)
+	if end < 0 {
+		return nil, nil, false
+	}
+	raw = []byte(rest[:end])
+	after := rest[end+1+len(delim):]
+	if i := strings.IndexByte(after, '\n'); i >= 0 {
+		body = []byte(after[i+1:])
+	}
+	return raw, body, true
+}
+
+func tomlUnmarshal(raw []byte, v interface{}) error {
+	_, err := toml.Decode(string(raw), v)
+	return err
+}
+
+// decodeFrontMatter unmarshals raw twice with unmarshal: once into the
+// typed FrontMatter, once into a generic map, so that whatever isn't
+// one of the known fields ends up in Params.
+func decodeFrontMatter(raw []byte, unmarshal func([]byte, interface{}) error) FrontMatter {
+	var fm FrontMatter
+	dieOnError(unmarshal(raw, &fm))
+	all := map[string]interface{}{}
+	dieOnError(unmarshal(raw, &all))
+	fm.Params = paramsOnly(all)
+	return fm
+}
+
+// splitJSONFrontMatter decodes a leading JSON object front matter block,
+// relying on json.Decoder to report exactly where the object ends so
+// the remainder can be fed to the markdown renderer untouched.
+func splitJSONFrontMatter(dat []byte) (FrontMatter, []byte, bool) {
+	dec := json.NewDecoder(bytes.NewReader(dat))
+	var fm FrontMatter
+	if err := dec.Decode(&fm); err != nil {
+		return FrontMatter{}, nil, false
+	}
+	offset := dec.InputOffset()
+	var all map[string]interface{}
+	dieOnError(json.Unmarshal(dat[:offset], &all))
+	fm.Params = paramsOnly(all)
+	body := dat[offset:]
+	if i := bytes.IndexByte(body, '\n'); i >= 0 {
+		body = body[i+1:]
+	}
+	return fm, body, true
+}
+
+func paramsOnly(all map[string]interface{}) map[string]interface{} {
+	for k := range all {
+		if knownFrontMatterKeys[strings.ToLower(k)] {
+			delete(all, k)
+		}
+	}
+	return all
+}
@@ -0,0 +1,83 @@
+// See LICENSE file for copyright and license details.
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseFrontMatter(t *testing.T) {
+	cases := []struct {
+		name     string
+		dat      string
+		wantFM   FrontMatter
+		wantBody string
+	}{
+		{
+			name: "yaml",
+			dat: "---\n" +
+				"title: Hello\n" +
+				"date: 2024-01-02T00:00:00Z\n" +
+				"draft: true\n" +
+				"weight: 3\n" +
+				"tags: [a, b]\n" +
+				"---\n" +
+				"body text\n",
+			wantFM: FrontMatter{
+				Title:  "Hello",
+				Date:   time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+				Draft:  true,
+				Weight: 3,
+				Params: map[string]interface{}{"tags": []interface{}{"a", "b"}},
+			},
+			wantBody: "body text\n",
+		},
+		{
+			name: "toml",
+			dat: "+++\n" +
+				"title = \"Hello\"\n" +
+				"weight = 5\n" +
+				"+++\n" +
+				"body text\n",
+			wantFM: FrontMatter{
+				Title:  "Hello",
+				Weight: 5,
+				Params: map[string]interface{}{},
+			},
+			wantBody: "body text\n",
+		},
+		{
+			name:     "json",
+			dat:      "{\"title\": \"Hello\", \"weight\": 2}\nbody text\n",
+			wantFM:   FrontMatter{Title: "Hello", Weight: 2, Params: map[string]interface{}{}},
+			wantBody: "body text\n",
+		},
+		{
+			name:     "no front matter",
+			dat:      "# just a heading\n",
+			wantFM:   FrontMatter{},
+			wantBody: "# just a heading\n",
+		},
+		{
+			name:     "unterminated delimiter falls through unchanged",
+			dat:      "---\ntitle: Hello\nbody text\n",
+			wantFM:   FrontMatter{},
+			wantBody: "---\ntitle: Hello\nbody text\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fm, body := parseFrontMatter([]byte(c.dat))
+			if fm.Title != c.wantFM.Title || fm.Draft != c.wantFM.Draft || fm.Weight != c.wantFM.Weight || !fm.Date.Equal(c.wantFM.Date) {
+				t.Fatalf("parseFrontMatter(%q) fm = %+v, want %+v", c.dat, fm, c.wantFM)
+			}
+			if len(fm.Params) != len(c.wantFM.Params) {
+				t.Fatalf("parseFrontMatter(%q) params = %+v, want %+v", c.dat, fm.Params, c.wantFM.Params)
+			}
+			if string(body) != c.wantBody {
+				t.Fatalf("parseFrontMatter(%q) body = %q, want %q", c.dat, body, c.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+// See LICENSE file for copyright and license details.
+package main
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/gomarkdown/markdown/ast"
+	"github.com/gomarkdown/markdown/parser"
+)
+
+// geminiWriter walks a gomarkdown AST and emits gemtext, as gmnhg does
+// for Hugo. Links can't be inlined in gemtext, so they're collected
+// while a block's text is gathered and flushed as standalone "=>"
+// lines right after it.
+type geminiWriter struct {
+	buf   strings.Builder
+	links []string
+}
+
+// renderGemini converts markdown source into gemtext.
+func renderGemini(dat []byte) []byte {
+	doc := parser.NewWithExtensions(parser.CommonExtensions).Parse(dat)
+	g := &geminiWriter{}
+	ast.WalkFunc(doc, g.visit)
+	return []byte(g.buf.String())
+}
+
+func (g *geminiWriter) visit(node ast.Node, entering bool) ast.WalkStatus {
+	if !entering {
+		return ast.GoToNext
+	}
+	switch n := node.(type) {
+	case *ast.Heading:
+		level := n.Level
+		if level > 3 {
+			level = 3
+		}
+		g.buf.WriteString(strings.Repeat("#", level) + " " + g.text(n) + "\n")
+		g.flushLinks()
+		return ast.SkipChildren
+	case *ast.Paragraph:
+		g.buf.WriteString(g.text(n) + "\n")
+		g.flushLinks()
+		return ast.SkipChildren
+	case *ast.ListItem:
+		g.buf.WriteString("* " + g.text(n) + "\n")
+		return ast.SkipChildren
+	case *ast.BlockQuote:
+		g.buf.WriteString("> " + g.text(n) + "\n")
+		g.flushLinks()
+		return ast.SkipChildren
+	case *ast.CodeBlock:
+		fence := "```"
+		if lang := strings.Fields(string(n.Info)); len(lang) > 0 {
+			fence += lang[0]
+		}
+		g.buf.WriteString(fence + "\n")
+		g.buf.Write(n.Literal)
+		if !bytes.HasSuffix(n.Literal, []byte("\n")) {
+			g.buf.WriteString("\n")
+		}
+		g.buf.WriteString("```\n")
+		return ast.SkipChildren
+	}
+	return ast.GoToNext
+}
+
+// text gathers the plain-text content of n, recording any links found
+// along the way so the caller can flush them afterwards.
+func (g *geminiWriter) text(n ast.Node) string {
+	var sb strings.Builder
+	ast.WalkFunc(n, func(node ast.Node, entering bool) ast.WalkStatus {
+		if !entering {
+			return ast.GoToNext
+		}
+		switch t := node.(type) {
+		case *ast.Text:
+			sb.Write(t.Literal)
+		case *ast.Code:
+			sb.Write(t.Literal)
+		case *ast.Link:
+			g.links = append(g.links, string(t.Destination))
+		case *ast.Softbreak, *ast.Hardbreak:
+			sb.WriteString(" ")
+		}
+		return ast.GoToNext
+	})
+	return sb.String()
+}
+
+func (g *geminiWriter) flushLinks() {
+	for _, l := range g.links {
+		g.buf.WriteString("=> " + l + "\n")
+	}
+	g.links = nil
+}
@@ -0,0 +1,52 @@
+// See LICENSE file for copyright and license details.
+package main
+
+import "testing"
+
+func TestRenderGemini(t *testing.T) {
+	cases := []struct {
+		name string
+		md   string
+		want string
+	}{
+		{
+			name: "headings",
+			md:   "# One\n## Two\n### Three\n#### Four\n",
+			want: "# One\n## Two\n### Three\n### Four\n",
+		},
+		{
+			name: "paragraph",
+			md:   "Hello *world*.\n",
+			want: "Hello world.\n",
+		},
+		{
+			name: "link after paragraph",
+			md:   "See [staw](https://example.com/staw) for details.\n",
+			want: "See staw for details.\n=> https://example.com/staw\n",
+		},
+		{
+			name: "list item",
+			md:   "* one\n* two\n",
+			want: "* one\n* two\n",
+		},
+		{
+			name: "blockquote",
+			md:   "> quoted text\n",
+			want: "> quoted text\n",
+		},
+		{
+			name: "fenced code block keeps language and content",
+			md:   "```go\nfmt.Println(1)\n```\n",
+			want: "```go\nfmt.Println(1)\n```\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := string(renderGemini([]byte(c.md)))
+			if got != c.want {
+				t.Fatalf("renderGemini(%q) = %q, want %q", c.md, got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+// See LICENSE file for copyright and license details.
+package main
+
+import (
+	"html/template"
+	"os"
+	"path/filepath"
+)
+
+// renderHtmlPage picks the right template for page and executes it with
+// html/template, which auto-escapes interpolated fields such as the
+// site title or front-matter params.
+//
+// Without -layouts, a.tpl is parsed and executed directly, matching
+// staw's historical single flat template behaviour. With -layouts, a
+// Hugo-style lookup is used: a base template at
+// layouts/_default/baseof.html defines named blocks ("head", "title",
+// "main") that layouts/_default/single.html or list.html override with
+// {{ define }}, and walk (the page's directory path, not including its
+// own filename) can supply a section override at
+// layouts/<walk[0]>/single.html or list.html. Everything under
+// layouts/partials/*.html is parsed alongside so it can be referenced
+// with {{ template }}.
+func renderHtmlPage(a Args, dst string, walk []string, isList bool) {
+	var t *template.Template
+	var err error
+	if a.layoutsDir == "" {
+		t, err = template.ParseFiles(a.tpl)
+		dieOnError(err)
+		out, err := os.Create(dst)
+		dieOnError(err)
+		defer out.Close()
+		dieOnError(t.Execute(out, a.page))
+		return
+	}
+
+	kind := "single.html"
+	if isList {
+		kind = "list.html"
+	}
+	leaf := filepath.Join(a.layoutsDir, "_default", kind)
+	if len(walk) > 0 {
+		if override := filepath.Join(a.layoutsDir, walk[0], kind); fileExists(override) {
+			leaf = override
+		}
+	}
+	files := []string{filepath.Join(a.layoutsDir, "_default", "baseof.html"), leaf}
+	partials, err := filepath.Glob(filepath.Join(a.layoutsDir, "partials", "*.html"))
+	dieOnError(err)
+	files = append(files, partials...)
+
+	t, err = template.ParseFiles(files...)
+	dieOnError(err)
+	out, err := os.Create(dst)
+	dieOnError(err)
+	defer out.Close()
+	dieOnError(t.ExecuteTemplate(out, "baseof.html", a.page))
+}
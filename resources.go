@@ -0,0 +1,175 @@
+// See LICENSE file for copyright and license details.
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// imageCacheDir holds generated image derivatives, keyed by source hash
+// and resize spec, so repeat builds don't redo the work.
+const imageCacheDir = ".staw-cache/images"
+
+// Resource is a non-markdown file living alongside an index.md, as in a
+// Hugo page bundle. Templates reach it via Page.Resources.GetMatch and
+// can derive resized images from it with Resize/Fill/Fit.
+type Resource struct {
+	Name    string
+	srcPath string
+	outDir  string
+	stats   *BuildStats
+	section string
+}
+
+// Resources is the set of Resource values attached to a bundle page.
+type Resources []*Resource
+
+// GetMatch returns the first resource whose name matches pattern (a
+// filepath.Match glob), or nil.
+func (rs Resources) GetMatch(pattern string) *Resource {
+	for _, r := range rs {
+		if ok, _ := filepath.Match(pattern, r.Name); ok {
+			return r
+		}
+	}
+	return nil
+}
+
+func (r *Resource) String() string {
+	return r.Name
+}
+
+// Resize scales the image to spec ("WIDTHxHEIGHT", either may be 0 to
+// preserve the aspect ratio) and returns the derivative's filename,
+// published next to the page.
+func (r *Resource) Resize(spec string) string {
+	return r.derive("resize", spec)
+}
+
+// Fill crops and scales the image to exactly spec's dimensions,
+// anchored as spec's second field (e.g. "800x400 center").
+func (r *Resource) Fill(spec string) string {
+	return r.derive("fill", spec)
+}
+
+// Fit scales the image down to fit within spec's dimensions, preserving
+// the aspect ratio.
+func (r *Resource) Fit(spec string) string {
+	return r.derive("fit", spec)
+}
+
+func (r *Resource) derive(kind, spec string) string {
+	w, h, anchor, err := parseImageSpec(spec)
+	dieOnError(err)
+	ext := filepath.Ext(r.Name)
+	slug := kind + "_" + strings.NewReplacer(" ", "_", "/", "_").Replace(spec)
+	derivative := sha1Hex(r.srcPath) + "_" + slug + ext
+	cachePath := filepath.Join(imageCacheDir, derivative)
+	if !fileExists(cachePath) {
+		img, err := imaging.Open(r.srcPath)
+		dieOnError(err)
+		dieOnError(os.MkdirAll(imageCacheDir, os.ModePerm))
+		dieOnError(imaging.Save(transformImage(kind, img, w, h, anchor), cachePath))
+		if r.stats != nil {
+			r.stats.AddImage(r.section)
+		}
+	}
+	copyFile(cachePath, filepath.Join(r.outDir, derivative))
+	return derivative
+}
+
+func transformImage(kind string, img image.Image, w, h int, anchor imaging.Anchor) image.Image {
+	switch kind {
+	case "fill":
+		return imaging.Fill(img, w, h, anchor, imaging.Lanczos)
+	case "fit":
+		return imaging.Fit(img, w, h, imaging.Lanczos)
+	default:
+		return imaging.Resize(img, w, h, imaging.Lanczos)
+	}
+}
+
+// parseImageSpec parses specs of the form "WIDTHxHEIGHT [anchor]", e.g.
+// "800x400 center".
+func parseImageSpec(spec string) (w, h int, anchor imaging.Anchor, err error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return 0, 0, imaging.Center, fmt.Errorf("invalid image spec %q: want \"WIDTHxHEIGHT [anchor]\"", spec)
+	}
+	dims := strings.SplitN(fields[0], "x", 2)
+	if dims[0] == "" {
+		return 0, 0, imaging.Center, fmt.Errorf("invalid image spec %q: want \"WIDTHxHEIGHT [anchor]\"", spec)
+	}
+	w, _ = strconv.Atoi(dims[0])
+	if len(dims) > 1 {
+		h, _ = strconv.Atoi(dims[1])
+	}
+	anchor = imaging.Center
+	if len(fields) > 1 {
+		anchor = anchorFromString(fields[1])
+	}
+	return w, h, anchor, nil
+}
+
+func anchorFromString(s string) imaging.Anchor {
+	switch s {
+	case "top":
+		return imaging.Top
+	case "bottom":
+		return imaging.Bottom
+	case "left":
+		return imaging.Left
+	case "right":
+		return imaging.Right
+	case "topleft":
+		return imaging.TopLeft
+	case "topright":
+		return imaging.TopRight
+	case "bottomleft":
+		return imaging.BottomLeft
+	case "bottomright":
+		return imaging.BottomRight
+	default:
+		return imaging.Center
+	}
+}
+
+func sha1Hex(path string) string {
+	dat, err := ioutil.ReadFile(path)
+	dieOnError(err)
+	sum := sha1.Sum(dat)
+	return hex.EncodeToString(sum[:])
+}
+
+// gatherResources copies every non-markdown sibling of an index.md into
+// a.dstPath and returns them as the page's Resources.
+func gatherResources(a Args, section string, files []os.FileInfo) Resources {
+	var resources Resources
+	for _, f := range files {
+		if f.IsDir() || isMdFile(f) {
+			continue
+		}
+		src := a.srcPath + "/" + f.Name()
+		dst := a.dstPath + "/" + f.Name()
+		copyFile(src, dst)
+		if a.stats != nil {
+			a.stats.AddStatic(section, fileSize(dst))
+		}
+		resources = append(resources, &Resource{Name: f.Name(), srcPath: src, outDir: a.dstPath, stats: a.stats, section: section})
+	}
+	return resources
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,51 @@
+// See LICENSE file for copyright and license details.
+package main
+
+import (
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func TestParseImageSpec(t *testing.T) {
+	cases := []struct {
+		name       string
+		spec       string
+		wantW      int
+		wantH      int
+		wantAnchor imaging.Anchor
+	}{
+		{name: "both dimensions", spec: "800x400", wantW: 800, wantH: 400, wantAnchor: imaging.Center},
+		{name: "width only preserves aspect", spec: "800x0", wantW: 800, wantH: 0, wantAnchor: imaging.Center},
+		{name: "missing height field", spec: "800", wantW: 800, wantH: 0, wantAnchor: imaging.Center},
+		{name: "named anchor", spec: "800x400 top", wantW: 800, wantH: 400, wantAnchor: imaging.Top},
+		{name: "unknown anchor defaults to center", spec: "800x400 nowhere", wantW: 800, wantH: 400, wantAnchor: imaging.Center},
+		{name: "bottomright anchor", spec: "800x400 bottomright", wantW: 800, wantH: 400, wantAnchor: imaging.BottomRight},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w, h, anchor, err := parseImageSpec(c.spec)
+			if err != nil {
+				t.Fatalf("parseImageSpec(%q) returned unexpected error: %v", c.spec, err)
+			}
+			if w != c.wantW || h != c.wantH {
+				t.Fatalf("parseImageSpec(%q) = (%d, %d), want (%d, %d)", c.spec, w, h, c.wantW, c.wantH)
+			}
+			if anchor != c.wantAnchor {
+				t.Fatalf("parseImageSpec(%q) anchor = %v, want %v", c.spec, anchor, c.wantAnchor)
+			}
+		})
+	}
+}
+
+func TestParseImageSpecInvalid(t *testing.T) {
+	cases := []string{"", "   ", "x400"}
+	for _, spec := range cases {
+		t.Run(spec, func(t *testing.T) {
+			if _, _, _, err := parseImageSpec(spec); err == nil {
+				t.Fatalf("parseImageSpec(%q) = nil error, want one", spec)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+// See LICENSE file for copyright and license details.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce coalesces a burst of fsnotify events (e.g. an editor
+// writing several files as part of one save) into a single rebuild.
+const watchDebounce = 150 * time.Millisecond
+
+// reloadScript is injected into every served HTML page and opens an SSE
+// connection that triggers a reload when staw pushes one.
+const reloadScript = `<script>new EventSource("/_staw/reload").onmessage = () => location.reload()</script>`
+
+// reloadBroker fans out a reload event to every connected browser tab.
+type reloadBroker struct {
+	mu      sync.Mutex
+	clients map[chan struct{}]bool
+}
+
+func newReloadBroker() *reloadBroker {
+	return &reloadBroker{clients: make(map[chan struct{}]bool)}
+}
+
+func (b *reloadBroker) serveSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	ch := make(chan struct{}, 1)
+	b.mu.Lock()
+	b.clients[ch] = true
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		delete(b.clients, ch)
+		b.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-ch:
+			fmt.Fprint(w, "data: reload\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (b *reloadBroker) broadcast() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.clients {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// reloadFileServer wraps an http.FileServer rooted at dir, injecting
+// reloadScript into any HTML response so the dev server can live-reload
+// the browser.
+func reloadFileServer(dir string) http.Handler {
+	fs := http.FileServer(http.Dir(dir))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := filepath.Join(dir, filepath.Clean(r.URL.Path))
+		if fi, err := os.Stat(p); err == nil && fi.IsDir() {
+			p = filepath.Join(p, "index.html")
+		}
+		if !strings.HasSuffix(p, ".html") {
+			fs.ServeHTTP(w, r)
+			return
+		}
+		dat, err := os.ReadFile(p)
+		if err != nil {
+			fs.ServeHTTP(w, r)
+			return
+		}
+		dat = bytes.Replace(dat, []byte("</body>"), []byte(reloadScript+"</body>"), 1)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(dat)
+	})
+}
+
+// serveAndWatch starts an http.FileServer over a.dstPath and rebuilds
+// the site whenever -in, -tpl (or -layouts), or css change, pushing a
+// live reload to connected browsers over /_staw/reload.
+func serveAndWatch(a Args, addr, css string) {
+	broker := newReloadBroker()
+	mux := http.NewServeMux()
+	mux.Handle("/_staw/reload", http.HandlerFunc(broker.serveSSE))
+	mux.Handle("/", reloadFileServer(a.dstPath))
+
+	go func() {
+		log.Printf("staw serving %s at %s", a.dstPath, addr)
+		dieOnError(http.ListenAndServe(addr, mux))
+	}()
+
+	watcher, err := fsnotify.NewWatcher()
+	dieOnError(err)
+	defer watcher.Close()
+	watchRecursively(watcher, a.srcPath)
+	if a.layoutsDir != "" {
+		watchRecursively(watcher, a.layoutsDir)
+	} else if a.tpl != "" {
+		dieOnError(watcher.Add(filepath.Dir(a.tpl)))
+	}
+	if css != "" {
+		dieOnError(watcher.Add(filepath.Dir(css)))
+	}
+
+	timer := time.NewTimer(watchDebounce)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	pending := map[string]bool{}
+
+	for {
+		select {
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 {
+				continue
+			}
+			if ev.Op&fsnotify.Create != 0 {
+				if fi, err := os.Stat(ev.Name); err == nil && fi.IsDir() {
+					watcher.Add(ev.Name)
+				}
+			}
+			pending[ev.Name] = true
+			timer.Reset(watchDebounce)
+		case <-timer.C:
+			rebuilt := false
+			for name := range pending {
+				if rebuildChanged(a, name, css) {
+					log.Println("rebuilding:", name)
+					rebuilt = true
+				}
+			}
+			pending = map[string]bool{}
+			if rebuilt {
+				broker.broadcast()
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Println("watch error:", err)
+		}
+	}
+}
+
+// watchRecursively adds every directory under root to w, since fsnotify
+// watches are not recursive on their own.
+func watchRecursively(w *fsnotify.Watcher, root string) {
+	filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+		if err != nil || fi == nil || !fi.IsDir() {
+			return nil
+		}
+		return w.Add(p)
+	})
+}
+
+// rebuildChanged reruns processPath over just the subtree of a.srcPath
+// that changed is rooted in, falling back to a full rebuild when changed
+// is the shared template or the layouts tree. changed is ignored (false
+// is returned, no rebuild happens) when it doesn't belong to -in, -tpl,
+// -layouts, or css at all, e.g. some other file that merely happens to
+// live alongside a watched directory.
+func rebuildChanged(a Args, changed, css string) bool {
+	if a.layoutsDir != "" {
+		if rel, err := filepath.Rel(a.layoutsDir, changed); err == nil && !strings.HasPrefix(rel, "..") {
+			processPath(a, []string{})
+			return true
+		}
+	} else if a.tpl != "" && filepath.Clean(changed) == filepath.Clean(a.tpl) {
+		processPath(a, []string{})
+		return true
+	}
+	if css != "" && filepath.Clean(changed) == filepath.Clean(css) {
+		f, err := os.Stat(css)
+		if err == nil {
+			copyFile(css, a.dstPath+"/"+f.Name())
+		}
+		return true
+	}
+	rel, err := filepath.Rel(a.srcPath, changed)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	top := strings.Split(rel, string(filepath.Separator))[0]
+	srcTop := filepath.Join(a.srcPath, top)
+	fi, err := os.Stat(srcTop)
+	if err != nil || !fi.IsDir() {
+		processPath(a, []string{})
+		return true
+	}
+	b := a
+	b.srcPath = srcTop
+	b.dstPath = filepath.Join(a.dstPath, top)
+	processPath(b, []string{top})
+	return true
+}
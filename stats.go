@@ -0,0 +1,115 @@
+// See LICENSE file for copyright and license details.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// SectionStats accumulates the processing counters for one top-level
+// content section, mirroring the idea behind Hugo's processing_stats.go.
+type SectionStats struct {
+	Pages  int   `json:"pages"`
+	Static int   `json:"static"`
+	Images int   `json:"images"`
+	Bytes  int64 `json:"bytes"`
+	Millis int64 `json:"ms"`
+}
+
+// BuildStats collects per-section counters for a single build. It is
+// threaded through Args rather than kept as package globals so callers
+// (and tests) can read the counters back directly.
+type BuildStats struct {
+	mu       sync.Mutex
+	sections map[string]*SectionStats
+}
+
+// NewBuildStats returns an empty BuildStats ready to accumulate counters.
+func NewBuildStats() *BuildStats {
+	return &BuildStats{sections: make(map[string]*SectionStats)}
+}
+
+func (b *BuildStats) section(name string) *SectionStats {
+	s, ok := b.sections[name]
+	if !ok {
+		s = &SectionStats{}
+		b.sections[name] = s
+	}
+	return s
+}
+
+// AddPage records one rendered page of the given byte size and render
+// time under section.
+func (b *BuildStats) AddPage(section string, bytes int64, d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.section(section)
+	s.Pages++
+	s.Bytes += bytes
+	s.Millis += d.Milliseconds()
+}
+
+// AddStatic records one copied static file under section.
+func (b *BuildStats) AddStatic(section string, bytes int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.section(section)
+	s.Static++
+	s.Bytes += bytes
+}
+
+// AddImage records one resized image derivative under section.
+func (b *BuildStats) AddImage(section string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.section(section).Images++
+}
+
+// Print writes a tab-aligned summary table to w, one row per section.
+func (b *BuildStats) Print(w io.Writer) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "section\tpages\tstatic\timages\tms")
+	for _, name := range b.sortedSections() {
+		s := b.sections[name]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\n", name, s.Pages, s.Static, s.Images, s.Millis)
+	}
+	tw.Flush()
+}
+
+// WriteJSON dumps the raw per-section counters as JSON to path, for CI
+// consumption.
+func (b *BuildStats) WriteJSON(path string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	dat, err := json.MarshalIndent(b.sections, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, dat, 0644)
+}
+
+func (b *BuildStats) sortedSections() []string {
+	names := make([]string, 0, len(b.sections))
+	for name := range b.sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sectionOf returns the top-level section a page under walk belongs to,
+// or "(root)" for pages at the site root.
+func sectionOf(walk []string) string {
+	if len(walk) == 0 {
+		return "(root)"
+	}
+	return walk[0]
+}
@@ -0,0 +1,95 @@
+// See LICENSE file for copyright and license details.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBuildStatsAdd(t *testing.T) {
+	b := NewBuildStats()
+	b.AddPage("blog", 100, 10*time.Millisecond)
+	b.AddPage("blog", 200, 5*time.Millisecond)
+	b.AddStatic("blog", 50)
+	b.AddImage("blog")
+	b.AddPage("(root)", 10, 0)
+
+	cases := []struct {
+		name string
+		want SectionStats
+	}{
+		{name: "blog", want: SectionStats{Pages: 2, Static: 1, Images: 1, Bytes: 350, Millis: 15}},
+		{name: "(root)", want: SectionStats{Pages: 1, Bytes: 10}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := *b.section(c.name)
+			if got != c.want {
+				t.Fatalf("section(%q) = %+v, want %+v", c.name, got, c.want)
+			}
+		})
+	}
+}
+
+func TestBuildStatsPrint(t *testing.T) {
+	b := NewBuildStats()
+	b.AddPage("blog", 100, 10*time.Millisecond)
+	b.AddStatic("(root)", 20)
+
+	var buf bytes.Buffer
+	b.Print(&buf)
+
+	want := "section  pages  static  images  ms\n" +
+		"(root)   0      1       0       0\n" +
+		"blog     1      0       0       10\n"
+	if buf.String() != want {
+		t.Fatalf("Print() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestBuildStatsWriteJSON(t *testing.T) {
+	b := NewBuildStats()
+	b.AddPage("blog", 100, 10*time.Millisecond)
+
+	path := filepath.Join(t.TempDir(), "stats.json")
+	if err := b.WriteJSON(path); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile(%q) error = %v", path, err)
+	}
+	var got map[string]SectionStats
+	if err := json.Unmarshal(dat, &got); err != nil {
+		t.Fatalf("Unmarshal(%q) error = %v", dat, err)
+	}
+	want := map[string]SectionStats{"blog": {Pages: 1, Bytes: 100, Millis: 10}}
+	if len(got) != len(want) || got["blog"] != want["blog"] {
+		t.Fatalf("WriteJSON() wrote %+v, want %+v", got, want)
+	}
+}
+
+func TestSectionOf(t *testing.T) {
+	cases := []struct {
+		name string
+		walk []string
+		want string
+	}{
+		{name: "root", walk: nil, want: "(root)"},
+		{name: "top-level section", walk: []string{"blog"}, want: "blog"},
+		{name: "nested page keeps top-level section", walk: []string{"blog", "2024", "post.md"}, want: "blog"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := sectionOf(c.walk); got != c.want {
+				t.Fatalf("sectionOf(%v) = %q, want %q", c.walk, got, c.want)
+			}
+		})
+	}
+}
@@ -2,23 +2,34 @@
 package main
 
 import (
-	"bufio"
 	"flag"
 	"github.com/gomarkdown/markdown"
+	mdhtml "github.com/gomarkdown/markdown/html"
+	htmltemplate "html/template"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
+	"sort"
 	"strings"
-	"text/template"
+	texttemplate "text/template"
+	"time"
 )
 
 type Args struct {
-	siteDir string
-	srcPath string
-	dstPath string
-	tpl     string
-	page    Page
+	siteDir       string
+	srcPath       string
+	dstPath       string
+	tpl           string
+	drafts        bool
+	future        bool
+	chromaStyle   string
+	chromaClasses bool
+	format        string
+	geminiTpl     string
+	layoutsDir    string
+	stats         *BuildStats
+	page          Page
 }
 
 type Menu struct {
@@ -27,51 +38,83 @@ type Menu struct {
 	Name   string
 	Sel    bool
 	Items  []Menu
+	Weight int
+	Date   time.Time
 }
 
 type Page struct {
-	Site        string
-	SiteTitle   string
-	Prefix      string
-	Title       string
-	HtmlContent string
-	Items       []Menu
+	Site          string
+	SiteTitle     string
+	Prefix        string
+	Title         string
+	HtmlContent   htmltemplate.HTML
+	GeminiContent string
+	Items         []Menu
+	FrontMatter
+	Resources Resources
 }
 
-func buildMenu(cwd, path, prefix string, walk []string) []Menu {
+func buildMenu(cwd, path, prefix string, walk []string, drafts, future bool, ext string) []Menu {
 	files, err := ioutil.ReadDir(cwd)
 	dieOnError(err)
 	var menu []Menu
 	for _, f := range files {
 		sel := len(walk) > 0 && f.Name() == walk[0]
 		if f.IsDir() {
-			menu = buildMenuNode(menu, cwd, path, prefix, walk, sel, f)
+			menu = buildMenuNode(menu, cwd, path, prefix, walk, sel, f, drafts, future, ext)
 		} else if isMdFile(f) {
-			menu = buildMenuLeaf(menu, cwd, path, prefix, sel, f)
+			menu = buildMenuLeaf(menu, cwd, path, prefix, sel, f, drafts, future, ext)
 		}
 	}
+	sort.SliceStable(menu, func(i, j int) bool {
+		if menu[i].Weight != menu[j].Weight {
+			return menu[i].Weight < menu[j].Weight
+		}
+		return menu[i].Date.Before(menu[j].Date)
+	})
 	return menu
 }
 
-func buildMenuLeaf(menu []Menu, cwd, path, prefix string, sel bool, f os.FileInfo) []Menu {
-	title := getTitle(cwd + "/" + f.Name())
+func buildMenuLeaf(menu []Menu, cwd, path, prefix string, sel bool, f os.FileInfo, drafts, future bool, ext string) []Menu {
+	dat, err := ioutil.ReadFile(cwd + "/" + f.Name())
+	dieOnError(err)
+	fm, _ := parseFrontMatter(dat)
+	if fm.Draft && !drafts {
+		return menu
+	}
+	if isFuture(fm.Date) && !future {
+		return menu
+	}
 	tmp := strings.TrimSuffix(f.Name(), ".md")
+	title := fm.Title
+	if title == "" {
+		title = tmp
+	}
+	m := Menu{prefix, "", title, sel, nil, fm.Weight, fm.Date}
 	if tmp == "index" {
+		m.Path = path + "index." + ext
 		// prepend
-		return append([]Menu{Menu{prefix, path + "index.html", title, sel, nil}}, menu...)
+		return append([]Menu{m}, menu...)
 	} else {
-		return append(menu, Menu{prefix, path + tmp + "/index.html", title, sel, nil})
+		m.Path = path + tmp + "/index." + ext
+		return append(menu, m)
 	}
 }
 
-func buildMenuNode(menu []Menu, cwd, path, prefix string, walk []string, sel bool, f os.FileInfo) []Menu {
-	m := Menu{prefix, path + f.Name() + "/index.html", f.Name() + "/", sel, nil}
+func buildMenuNode(menu []Menu, cwd, path, prefix string, walk []string, sel bool, f os.FileInfo, drafts, future bool, ext string) []Menu {
+	m := Menu{prefix, path + f.Name() + "/index." + ext, f.Name() + "/", sel, nil, 0, time.Time{}}
 	if sel {
-		m.Items = buildMenu(cwd+"/"+f.Name(), path+f.Name()+"/", prefix, walk[1:])
+		m.Items = buildMenu(cwd+"/"+f.Name(), path+f.Name()+"/", prefix, walk[1:], drafts, future, ext)
 	}
 	return append(menu, m)
 }
 
+// isFuture reports whether t is a non-zero date after now, as used to
+// hide scheduled posts from a build unless -future is passed.
+func isFuture(t time.Time) bool {
+	return !t.IsZero() && t.After(time.Now())
+}
+
 func copyFile(src, dst string) {
 	in, err := os.Open(src)
 	dieOnError(err)
@@ -96,21 +139,11 @@ func dieOnError(err error) {
 	}
 }
 
-func getTitle(src string) string {
-	in, err := os.Open(src)
-	dieOnError(err)
-	defer in.Close()
-	reader := bufio.NewReader(in)
-	title, err := reader.ReadString('\n')
-	dieOnError(err)
-	return strings.TrimRight(title, "\n")
-}
-
 func isMdFile(f os.FileInfo) bool {
 	return strings.HasSuffix(f.Name(), ".md")
 }
 
-func mkDstPath(dstPath string, f os.FileInfo) string {
+func mkDstPath(dstPath string, f os.FileInfo, ext string) string {
 	var dst, tmp string
 	if f == nil {
 		tmp = "index" // no index.md in directory case
@@ -120,31 +153,84 @@ func mkDstPath(dstPath string, f os.FileInfo) string {
 	if tmp != "index" {
 		dst = dstPath + "/" + tmp
 		os.Mkdir(dst, os.ModePerm)
-		dst += "/index.html"
+		dst += "/index." + ext
 	} else {
-		dst = dstPath + "/index.html"
+		dst = dstPath + "/index." + ext
 	}
 	return dst
 }
 
 func processMdFile(a Args, walk []string, f os.FileInfo) {
+	start := time.Now()
+	section := sectionOf(walk)
+	dirWalk := walk
 	if f != nil {
 		walk = append(walk, f.Name())
-		a.page.Title = getTitle(a.srcPath)
 		dat, err := ioutil.ReadFile(a.srcPath)
 		dieOnError(err)
-		a.page.HtmlContent = string(markdown.ToHTML(dat, nil, nil))
+		fm, body := parseFrontMatter(dat)
+		if fm.Draft && !a.drafts {
+			return
+		}
+		if isFuture(fm.Date) && !a.future {
+			return
+		}
+		a.page.FrontMatter = fm
+		if fm.Title != "" {
+			a.page.Title = fm.Title
+		} else {
+			a.page.Title = strings.TrimSuffix(f.Name(), ".md")
+		}
+		if a.format != "gemini" {
+			renderer := mdhtml.NewRenderer(mdhtml.RendererOptions{
+				Flags:          mdhtml.CommonFlags,
+				RenderNodeHook: chromaRenderHook(a.chromaStyle, a.chromaClasses),
+			})
+			a.page.HtmlContent = htmltemplate.HTML(markdown.ToHTML(body, nil, renderer))
+		}
+		if a.format != "html" {
+			a.page.GeminiContent = string(renderGemini(body))
+		}
 	} else if len(walk) > 0 {
 		a.page.Title = walk[len(walk)-1] + "/"
 	}
-	a.page.Items = buildMenu(a.siteDir, "", a.page.Prefix, walk)
-	t, err := template.ParseFiles(a.tpl)
+	var bytesWritten int64
+	if a.format != "gemini" {
+		a.page.Items = buildMenu(a.siteDir, "", a.page.Prefix, walk, a.drafts, a.future, "html")
+		dst := mkDstPath(a.dstPath, f, "html")
+		renderHtmlPage(a, dst, dirWalk, f == nil)
+		bytesWritten += fileSize(dst)
+	}
+	if a.format != "html" {
+		a.page.Items = buildMenu(a.siteDir, "", a.page.Prefix, walk, a.drafts, a.future, "gmi")
+		dst := mkDstPath(a.dstPath, f, "gmi")
+		renderTextPage(a.geminiTpl, dst, a.page)
+		bytesWritten += fileSize(dst)
+	}
+	if a.stats != nil {
+		a.stats.AddPage(section, bytesWritten, time.Since(start))
+	}
+}
+
+// fileSize returns the size of path, or 0 if it cannot be stat'd.
+func fileSize(path string) int64 {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return fi.Size()
+}
+
+// renderTextPage executes tpl as a plain text/template, used for the
+// gemini output where html/template's auto-escaping would corrupt
+// gemtext.
+func renderTextPage(tpl, dst string, page Page) {
+	t, err := texttemplate.ParseFiles(tpl)
 	dieOnError(err)
-	dst := mkDstPath(a.dstPath, f)
 	out, err := os.Create(dst)
 	dieOnError(err)
 	defer out.Close()
-	dieOnError(t.Execute(out, a.page))
+	dieOnError(t.Execute(out, page))
 }
 
 func processPath(a Args, walk []string) {
@@ -152,6 +238,15 @@ func processPath(a Args, walk []string) {
 	files, err := ioutil.ReadDir(a.srcPath)
 	dieOnError(err)
 	noIndexMd := true
+	for _, f := range files {
+		if !f.IsDir() && f.Name() == "index.md" {
+			noIndexMd = false
+		}
+	}
+	var resources Resources
+	if !noIndexMd {
+		resources = gatherResources(a, sectionOf(walk), files)
+	}
 	for _, f := range files {
 		b := a
 		b.srcPath = a.srcPath + "/" + f.Name()
@@ -159,13 +254,17 @@ func processPath(a Args, walk []string) {
 			b.dstPath = a.dstPath + "/" + f.Name()
 			processPath(b, append(walk, f.Name()))
 		} else {
-			if f.Name() == "index.md" {
-				noIndexMd = false
-			}
 			if isMdFile(f) {
+				if f.Name() == "index.md" {
+					b.page.Resources = resources
+				}
 				processMdFile(b, walk, f)
-			} else {
-				copyFile(b.srcPath, b.dstPath+"/"+f.Name())
+			} else if noIndexMd {
+				dst := b.dstPath + "/" + f.Name()
+				copyFile(b.srcPath, dst)
+				if a.stats != nil {
+					a.stats.AddStatic(sectionOf(walk), fileSize(dst))
+				}
 			}
 		}
 	}
@@ -175,23 +274,55 @@ func processPath(a Args, walk []string) {
 }
 
 func main() {
-	tpl := flag.String("tpl", "default.tpl", "template file to be used (required)")
+	tpl := flag.String("tpl", "default.tpl", "template file to be used; a shortcut for a single baseof.html when -layouts is not given")
+	layouts := flag.String("layouts", "", "layouts/ directory to look up templates in, Hugo-style (optional, overrides -tpl)")
 	src := flag.String("in", "", "input site directory (required)")
 	dst := flag.String("out", "", "output site directory (required)")
 	title := flag.String("t", "", "site title of the site (required)")
 	prefix := flag.String("p", "", "url-prefix for local testing (optional)")
 	css := flag.String("css", "", "style.css file to be copied to site output directory (optional)")
+	drafts := flag.Bool("drafts", false, "include pages with draft: true in their front matter (optional)")
+	future := flag.Bool("future", false, "include pages with a future date in their front matter (optional, mainly useful with -serve)")
+	chromaStyle := flag.String("chroma-style", "github", "Chroma style used to highlight fenced code blocks")
+	chromaClasses := flag.Bool("chroma-classes", false, "emit CSS classes instead of inline styles for highlighted code")
+	chromaCSS := flag.String("chroma-css", "", "write the Chroma stylesheet for -chroma-style to this path (requires -chroma-classes)")
+	format := flag.String("format", "html", "output format: html, gemini, or both")
+	geminiTpl := flag.String("gemini-tpl", "default.gmi.tpl", "template file used for gemini output")
+	serve := flag.String("serve", "", "start a dev server at addr (e.g. :8080) after building, rebuilding and live-reloading on changes to -in, -tpl, or -css (optional)")
+	quiet := flag.Bool("quiet", false, "suppress the per-section build summary table (optional)")
+	statsJSON := flag.String("stats-json", "", "also dump the build counters as JSON to this path, for CI consumption (optional)")
 	flag.Parse()
-	dieIfEmpty(tpl, "no template given")
+	if *layouts == "" {
+		dieIfEmpty(tpl, "no template given")
+	}
 	dieIfEmpty(title, "no site title given")
 	dieIfEmpty(src, "no site input directory given")
 	dieIfEmpty(dst, "no output directory given")
+	switch *format {
+	case "html", "gemini", "both":
+	default:
+		log.Fatalf("unknown -format %q: want html, gemini, or both", *format)
+	}
 	site, err := os.Stat(*src)
 	dieOnError(err)
-	processPath(Args{*src, *src, *dst, *tpl, Page{site.Name(), *title, *prefix, "", "", nil}}, []string{})
+	stats := NewBuildStats()
+	a := Args{*src, *src, *dst, *tpl, *drafts, *future, *chromaStyle, *chromaClasses, *format, *geminiTpl, *layouts, stats, Page{site.Name(), *title, *prefix, "", "", "", nil, FrontMatter{}, nil}}
+	processPath(a, []string{})
 	if *css != "" {
 		f, err := os.Stat(*css)
 		dieOnError(err)
 		copyFile(*css, *dst+"/"+f.Name())
 	}
+	if *chromaClasses && *chromaCSS != "" {
+		writeChromaCSS(*chromaCSS, *chromaStyle)
+	}
+	if !*quiet {
+		stats.Print(os.Stderr)
+	}
+	if *statsJSON != "" {
+		dieOnError(stats.WriteJSON(*statsJSON))
+	}
+	if *serve != "" {
+		serveAndWatch(a, *serve, *css)
+	}
 }